@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rancher/machine/libmachine/log"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// resyncPeriod is the informer's periodic resync interval. 0 turns periodic
+// resync off, so the informer relies on its list-then-watch alone, the same
+// default used by agola's executor.
+const resyncPeriod = 0
+
+// podTerminalError is returned by waitForPodReady when the Pod (or one of
+// its containers) has reached a state it cannot recover from on its own, so
+// the driver should stop waiting and clean up instead of blocking until the
+// context deadline.
+type podTerminalError struct {
+	Reason  string
+	Message string
+}
+
+func (e *podTerminalError) Error() string {
+	return fmt.Sprintf("pod will not become ready: %s: %s", e.Reason, e.Message)
+}
+
+// defaultRetryExhaustedReasons are waiting-container reasons that indicate
+// kubelet has already given up retrying the container itself, so the driver
+// should surface a terminal error rather than keep waiting.
+var defaultRetryExhaustedReasons = map[string]bool{
+	"ImagePullBackOff": true,
+	"ErrImagePull":     true,
+	"CrashLoopBackOff": true,
+	"InvalidImageName": true,
+}
+
+// waitForPodReady watches namespace/name through a SharedInformerFactory
+// (instead of a one-shot Pods().Watch with a hard-coded timeout) and returns
+// the Pod's IP once it, and all of its containers, are Ready. It replaces the
+// duplicated watch loops that used to live in getWaitForIP and Start.
+//
+// Unlike a raw Watch, the informer transparently re-establishes its watch on
+// apiserver disconnects (list-then-watch with its own backoff), so a blip in
+// connectivity does not silently drop the event that would have unblocked
+// us. Terminal Pod/container states are reported as a *podTerminalError so
+// callers can tell "still starting" apart from "never going to start". A
+// still-starting Pod is requeued through the workqueue's exponential
+// backoff (not just re-checked on the next informer event) to bound how
+// often we re-inspect it. The wait queue is shut down as soon as ctx is
+// done, so a Pod that never produces another event cannot block Get()
+// past ctx's deadline.
+func waitForPodReady(ctx context.Context, k8s kubernetes.Interface, namespace, name string) (string, error) {
+	factory := informers.NewSharedInformerFactoryWithOptions(k8s, resyncPeriod, informers.WithNamespace(namespace))
+	pods := factory.Core().V1().Pods()
+	events := factory.Core().V1().Events()
+
+	// A plain workqueue only unblocks Get() on ShutDown or a fresh Add/Update
+	// event; it is never told about ctx on its own. Tie the two together so a
+	// Pod that never produces another event (e.g. it's stuck Pending and only
+	// Events, which we merely log, stream in) still unblocks Get() once ctx's
+	// deadline passes, instead of hanging past it.
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "pod-"+name)
+	defer queue.ShutDown()
+	go func() {
+		<-ctx.Done()
+		queue.ShutDown()
+	}()
+
+	enqueue := func(obj interface{}) {
+		if pod, ok := obj.(*corev1.Pod); ok && pod.Name == name {
+			queue.Add(name)
+		}
+	}
+	if _, err := pods.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    enqueue,
+		UpdateFunc: func(_, obj interface{}) { enqueue(obj) },
+	}); err != nil {
+		return "", err
+	}
+
+	if _, err := events.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if event, ok := obj.(*corev1.Event); ok && event.InvolvedObject.Name == name {
+				log.Infof("%s/%s: %s", namespace, name, event.Message)
+			}
+		},
+	}); err != nil {
+		return "", err
+	}
+
+	stopCh := ctx.Done()
+	go factory.Start(stopCh)
+	if !cache.WaitForCacheSync(stopCh, pods.Informer().HasSynced, events.Informer().HasSynced) {
+		return "", ctx.Err()
+	}
+
+	// The initial List already populated the store, so there may be no
+	// further Add/Update event for a Pod that's already Ready by the time
+	// we get here. Seed the queue once up front to cover that case.
+	queue.Add(name)
+
+	for {
+		key, shutdown := queue.Get()
+		if shutdown {
+			if err := ctx.Err(); err != nil {
+				return "", fmt.Errorf("timed out waiting for pod %s/%s to become ready: %v", namespace, name, err)
+			}
+			return "", fmt.Errorf("stopped waiting for pod %s/%s to become ready", namespace, name)
+		}
+
+		ip, terminal, ready, err := checkPodReady(pods.Informer().GetStore(), namespace, key.(string))
+		queue.Done(key)
+		if err != nil {
+			// Transient error reading the local cache: retry with the
+			// queue's exponential backoff rather than failing the wait.
+			queue.AddRateLimited(key)
+			continue
+		}
+		if terminal != nil {
+			queue.Forget(key)
+			return "", terminal
+		}
+		if ready {
+			queue.Forget(key)
+			return ip, nil
+		}
+
+		// Still starting: requeue with exponential backoff instead of
+		// relying solely on the next informer event, so a Pod that only
+		// produces Events (which we merely log) still gets rechecked.
+		queue.AddRateLimited(key)
+	}
+}
+
+// checkPodReady inspects the cached Pod named name and reports whether it is
+// ready, whether it has failed terminally, or whether the caller should keep
+// waiting.
+func checkPodReady(store cache.Store, namespace, name string) (ip string, terminal *podTerminalError, ready bool, err error) {
+	obj, exists, err := store.GetByKey(namespace + "/" + name)
+	if err != nil {
+		return "", nil, false, err
+	}
+	if !exists {
+		return "", nil, false, nil
+	}
+	pod := obj.(*corev1.Pod)
+
+	switch pod.Status.Phase {
+	case corev1.PodFailed:
+		return "", &podTerminalError{Reason: "Failed", Message: pod.Status.Message}, false, nil
+	case corev1.PodSucceeded:
+		return "", &podTerminalError{Reason: "Succeeded", Message: "pod exited before it was used"}, false, nil
+	}
+
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.State.Waiting != nil && defaultRetryExhaustedReasons[cs.State.Waiting.Reason] {
+			return "", &podTerminalError{Reason: cs.State.Waiting.Reason, Message: cs.State.Waiting.Message}, false, nil
+		}
+	}
+
+	if pod.Status.PodIP == "" {
+		return "", nil, false, nil
+	}
+
+	for _, cs := range pod.Status.ContainerStatuses {
+		if !cs.Ready {
+			return "", nil, false, nil
+		}
+	}
+
+	return pod.Status.PodIP, nil, true, nil
+}
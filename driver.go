@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -16,23 +15,67 @@ import (
 	"github.com/rancher/wrangler/pkg/apply"
 	"github.com/rancher/wrangler/pkg/objectset"
 	corev1 "k8s.io/api/core/v1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
 // Driver contains kubernetes-specific data to implement [drivers.Driver]
 type Driver struct {
 	*drivers.BaseDriver
-	Userdata string
-	Image    string
+	Userdata      string
+	Image         string
+	PodConnection string
+
+	PodStorageClass string
+	PodDiskSize     string
+	PodExtraMounts  string
+
+	PodCPURequest      string
+	PodCPULimit        string
+	PodMemoryRequest   string
+	PodMemoryLimit     string
+	PodNodeSelector    string
+	PodTolerations     string
+	PodAffinityFile    string
+	PodImagePullSecret string
+	PodServiceAccount  string
+	PodPriorityClass   string
+	PodLabels          string
+	PodAnnotations     string
+	PodRuntimeClass    string
+
+	PodReplicas int
+	PodRole     string
+
+	localPort int
+	stopExec  chan struct{}
 }
 
 const (
-	defaultUser  = "pod-user"
-	defaultImage = "ghcr.io/william86370/rke2ink:systemd"
-	defaultPort = 22
+	defaultUser          = "pod-user"
+	defaultImage         = "ghcr.io/william86370/rke2ink:systemd"
+	defaultPort          = 22
+	defaultPodConnection = podConnectionSSH
+	defaultPodDiskSize   = "10Gi"
+	defaultPodReplicas   = 1
+	defaultPodRole       = podRoleServer
+
+	// podConnectionSSH tunnels everything over SSH to the Pod IP, same as before.
+	podConnectionSSH = "ssh"
+	// podConnectionExec reaches the Pod's docker daemon port through the
+	// Kubernetes portforward subresource instead of tunneling over sshd.
+	// Provisioning still happens over SSH either way (see Create/Start); it
+	// does not provide its own command-running path for the bootstrapper,
+	// see portforward.go.
+	podConnectionExec = "exec"
+
+	// podRoleServer joins additional replicas to the lead pod as RKE2 servers
+	// (HA control-plane); podRoleAgent joins them as RKE2 agents (workers).
+	podRoleServer = "server"
+	podRoleAgent  = "agent"
 )
 
 
@@ -69,19 +112,198 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			EnvVar: "POD_IMAGE",
 			Value:  "",
 		},
+		mcnflag.StringFlag{
+			Name:   "pod-connection",
+			Usage:  "How the driver reaches the docker daemon port: \"ssh\" tunnels over sshd on the Pod, \"exec\" port-forwards it through the Kubernetes portforward subresource. Provisioning always happens over SSH either way",
+			EnvVar: "POD_CONNECTION",
+			Value:  defaultPodConnection,
+		},
+		mcnflag.StringFlag{
+			Name:   "pod-storage-class",
+			Usage:  "StorageClass for the PersistentVolumeClaim backing the pod's rancher state (empty uses the cluster default)",
+			EnvVar: "POD_STORAGE_CLASS",
+			Value:  "",
+		},
+		mcnflag.StringFlag{
+			Name:   "pod-disk-size",
+			Usage:  "Size of the PersistentVolumeClaim backing the pod's rancher state",
+			EnvVar: "POD_DISK_SIZE",
+			Value:  defaultPodDiskSize,
+		},
+		mcnflag.StringFlag{
+			Name:   "pod-extra-mounts",
+			Usage:  "Extra paths on the state PVC to mount into the pod, as hostPath:mountPath pairs separated by commas",
+			EnvVar: "POD_EXTRA_MOUNTS",
+			Value:  "",
+		},
+		mcnflag.StringFlag{
+			Name:   "pod-cpu-request",
+			Usage:  "CPU request for the machine container",
+			EnvVar: "POD_CPU_REQUEST",
+			Value:  "",
+		},
+		mcnflag.StringFlag{
+			Name:   "pod-cpu-limit",
+			Usage:  "CPU limit for the machine container",
+			EnvVar: "POD_CPU_LIMIT",
+			Value:  "",
+		},
+		mcnflag.StringFlag{
+			Name:   "pod-memory-request",
+			Usage:  "Memory request for the machine container",
+			EnvVar: "POD_MEMORY_REQUEST",
+			Value:  "",
+		},
+		mcnflag.StringFlag{
+			Name:   "pod-memory-limit",
+			Usage:  "Memory limit for the machine container",
+			EnvVar: "POD_MEMORY_LIMIT",
+			Value:  "",
+		},
+		mcnflag.StringFlag{
+			Name:   "pod-node-selector",
+			Usage:  "Node selector for the pod, as key=value pairs separated by commas",
+			EnvVar: "POD_NODE_SELECTOR",
+			Value:  "",
+		},
+		mcnflag.StringFlag{
+			Name:   "pod-tolerations",
+			Usage:  "Tolerations for the pod, as key=value:Effect entries separated by commas",
+			EnvVar: "POD_TOLERATIONS",
+			Value:  "",
+		},
+		mcnflag.StringFlag{
+			Name:   "pod-affinity-file",
+			Usage:  "Path to a JSON or YAML file merged into the pod's Spec.Affinity",
+			EnvVar: "POD_AFFINITY_FILE",
+			Value:  "",
+		},
+		mcnflag.StringFlag{
+			Name:   "pod-image-pull-secret",
+			Usage:  "Name(s) of the image pull secret(s) for the machine container, separated by commas",
+			EnvVar: "POD_IMAGE_PULL_SECRET",
+			Value:  "",
+		},
+		mcnflag.StringFlag{
+			Name:   "pod-service-account",
+			Usage:  "ServiceAccount the pod runs as",
+			EnvVar: "POD_SERVICE_ACCOUNT",
+			Value:  "",
+		},
+		mcnflag.StringFlag{
+			Name:   "pod-priority-class",
+			Usage:  "PriorityClass for the pod",
+			EnvVar: "POD_PRIORITY_CLASS",
+			Value:  "",
+		},
+		mcnflag.StringFlag{
+			Name:   "pod-labels",
+			Usage:  "Extra labels for the pod, as key=value pairs separated by commas",
+			EnvVar: "POD_LABELS",
+			Value:  "",
+		},
+		mcnflag.StringFlag{
+			Name:   "pod-annotations",
+			Usage:  "Extra annotations for the pod, as key=value pairs separated by commas",
+			EnvVar: "POD_ANNOTATIONS",
+			Value:  "",
+		},
+		mcnflag.StringFlag{
+			Name:   "pod-runtime-class",
+			Usage:  "RuntimeClass for the pod",
+			EnvVar: "POD_RUNTIME_CLASS",
+			Value:  "",
+		},
+		mcnflag.IntFlag{
+			Name:   "pod-replicas",
+			Usage:  "Number of pods to provision as a single RKE2 cluster, with stable hostnames <machine>-0..<machine>-N-1",
+			EnvVar: "POD_REPLICAS",
+			Value:  defaultPodReplicas,
+		},
+		mcnflag.StringFlag{
+			Name:   "pod-role",
+			Usage:  "RKE2 role that replicas after the first join as: \"server\" (HA control-plane) or \"agent\" (worker)",
+			EnvVar: "POD_ROLE",
+			Value:  defaultPodRole,
+		},
 	}
-
+}
 
 // SetConfigFromFlags initializes the driver based on the command line flags.
 func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.Userdata = flags.String("pod-userdata")
 	d.Image = flags.String("pod-image")
+	d.PodConnection = flags.String("pod-connection")
+	d.PodStorageClass = flags.String("pod-storage-class")
+	d.PodDiskSize = flags.String("pod-disk-size")
+	d.PodExtraMounts = flags.String("pod-extra-mounts")
+	d.PodCPURequest = flags.String("pod-cpu-request")
+	d.PodCPULimit = flags.String("pod-cpu-limit")
+	d.PodMemoryRequest = flags.String("pod-memory-request")
+	d.PodMemoryLimit = flags.String("pod-memory-limit")
+	d.PodNodeSelector = flags.String("pod-node-selector")
+	d.PodTolerations = flags.String("pod-tolerations")
+	d.PodAffinityFile = flags.String("pod-affinity-file")
+	d.PodImagePullSecret = flags.String("pod-image-pull-secret")
+	d.PodServiceAccount = flags.String("pod-service-account")
+	d.PodPriorityClass = flags.String("pod-priority-class")
+	d.PodLabels = flags.String("pod-labels")
+	d.PodAnnotations = flags.String("pod-annotations")
+	d.PodRuntimeClass = flags.String("pod-runtime-class")
+	d.PodReplicas = flags.Int("pod-replicas")
+	d.PodRole = flags.String("pod-role")
 	d.SetSwarmConfigFromFlags(flags)
 
 	if d.Image == "" {
 		d.Image = defaultImage
 	}
 
+	if d.PodConnection == "" {
+		d.PodConnection = defaultPodConnection
+	}
+
+	if d.PodConnection != podConnectionSSH && d.PodConnection != podConnectionExec {
+		return fmt.Errorf("invalid --pod-connection %q: must be %q or %q", d.PodConnection, podConnectionSSH, podConnectionExec)
+	}
+
+	if d.PodDiskSize == "" {
+		d.PodDiskSize = defaultPodDiskSize
+	}
+
+	if _, err := parseExtraMounts(d.PodExtraMounts); err != nil {
+		return err
+	}
+
+	if _, err := parseResourceList(d.PodCPURequest, d.PodMemoryRequest); err != nil {
+		return err
+	}
+	if _, err := parseResourceList(d.PodCPULimit, d.PodMemoryLimit); err != nil {
+		return err
+	}
+	if _, err := parseKeyValues(d.PodNodeSelector); err != nil {
+		return err
+	}
+	if _, err := parseKeyValues(d.PodLabels); err != nil {
+		return err
+	}
+	if _, err := parseKeyValues(d.PodAnnotations); err != nil {
+		return err
+	}
+	if _, err := parseTolerations(d.PodTolerations); err != nil {
+		return err
+	}
+
+	if d.PodReplicas <= 0 {
+		d.PodReplicas = defaultPodReplicas
+	}
+
+	if d.PodRole == "" {
+		d.PodRole = defaultPodRole
+	}
+	if d.PodRole != podRoleServer && d.PodRole != podRoleAgent {
+		return fmt.Errorf("invalid --pod-role %q: must be %q or %q", d.PodRole, podRoleServer, podRoleAgent)
+	}
+
 	return nil
 }
 
@@ -110,12 +332,21 @@ func (d *Driver) PreCreateCheck() error {
 		}
 	}
 
+	if d.PodAffinityFile != "" {
+		if _, err := parseAffinityFile(d.PodAffinityFile); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 
 // Create creates a pod VM instance acting as a docker host.
 func (d *Driver) Create() error {
+	// Exec mode only changes how GetURL reaches the docker daemon port
+	// (see portforward.go); the bootstrapper still provisions the pod over
+	// SSH either way, so the key pair is always needed.
 	log.Infof("Generating SSH Key")
 
 	if err := ssh.GenerateSSHKey(d.GetSSHKeyPath()); err != nil {
@@ -128,62 +359,51 @@ func (d *Driver) Create() error {
 
 
 func getWaitForIP(ctx context.Context, k8s kubernetes.Interface, namespace, name string) (string, error) {
-	_, err := k8s.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
-	if err != nil {
-		return "", err
-	}
-
-	w, err := k8s.CoreV1().Pods(namespace).Watch(ctx, metav1.ListOptions{
-		FieldSelector:  "metadata.name=" + name,
-		TimeoutSeconds: &[]int64{600}[0],
-	})
-	if err != nil {
-		return "", err
-	}
-
-	var ip string
-	for event := range w.ResultChan() {
-		if pod, ok := event.Object.(*corev1.Pod); ok {
-			if pod.Status.PodIP != "" {
-				ip = pod.Status.PodIP
-				w.Stop()
-			}
-		}
-	}
-
-	if ip == "" {
-		return "", fmt.Errorf("failed to get IP of %s/%s", namespace, name)
-	}
-
-	return ip, nil
+	return waitForPodReady(ctx, k8s, namespace, name)
 }
 
 func getClient() (string, kubernetes.Interface, apply.Apply, error) {
+	ns, _, client, apply, err := getClientConfig()
+	return ns, client, apply, err
+}
+
+// getClientConfig is like getClient but also returns the *rest.Config, which
+// the exec-mode port-forward (see portforward.go) needs to open its own SPDY
+// streams.
+func getClientConfig() (string, *rest.Config, kubernetes.Interface, apply.Apply, error) {
 	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
 	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{})
 	ns, _, err := loader.Namespace()
 	if err != nil {
-		return "", nil, nil, err
+		return "", nil, nil, nil, err
 	}
 	cfg, err := loader.ClientConfig()
 	if err != nil {
-		return "", nil, nil, err
+		return "", nil, nil, nil, err
 	}
 	client, err := kubernetes.NewForConfig(cfg)
 	if err != nil {
-		return "", nil, nil, err
+		return "", nil, nil, nil, err
 	}
 	apply, err := apply.NewForConfig(cfg)
 	if err != nil {
-		return "", nil, nil, err
+		return "", nil, nil, nil, err
 	}
-	return ns, client, apply.WithDynamicLookup(), err
+	return ns, cfg, client, apply.WithDynamicLookup(), err
 }
 
 
 
 // GetURL returns the URL of the remote docker daemon.
 func (d *Driver) GetURL() (string, error) {
+	if d.PodConnection == podConnectionExec {
+		port, err := d.getOrCreatePortForward()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("tcp://%s", net.JoinHostPort("127.0.0.1", fmt.Sprintf("%d", port))), nil
+	}
+
 	ip, err := d.GetIP()
 	if err != nil {
 		return "", err
@@ -192,7 +412,7 @@ func (d *Driver) GetURL() (string, error) {
 	return fmt.Sprintf("tcp://%s", net.JoinHostPort(ip, "2376")), nil
 }
 
-// GetIP returns the IP address of the pod instance.
+// GetIP returns the IP address of the lead pod instance.
 func (d *Driver) GetIP() (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
 	defer cancel()
@@ -202,10 +422,11 @@ func (d *Driver) GetIP() (string, error) {
 		return "", err
 	}
 
-	return getWaitForIP(ctx, k8s, namespace, d.MachineName)
+	return getWaitForIP(ctx, k8s, namespace, leadName(d.MachineName))
 }
 
-// GetState returns a docker.hosts.state.State value representing the current state of the host.
+// GetState returns a docker.hosts.state.State value representing the current
+// state of the host. Running is only reported once every replica is Ready.
 func (d *Driver) GetState() (state.State, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
 	defer cancel()
@@ -215,21 +436,7 @@ func (d *Driver) GetState() (state.State, error) {
 		return state.None, err
 	}
 
-	pod, err := k8s.CoreV1().Pods(namespace).Get(ctx, d.MachineName, metav1.GetOptions{})
-	if apierrors.IsNotFound(err) {
-		return state.NotFound, nil
-	} else if err != nil {
-		return state.None, err
-	}
-
-	switch pod.Status.Phase {
-	case corev1.PodPending:
-		return state.Starting, nil
-	case corev1.PodRunning:
-		return state.Running, nil
-	default:
-		return state.Stopped, nil
-	}
+	return aggregateState(ctx, k8s, namespace, d.replicaNames())
 }
 
 // Start starts an existing pod instance or create an instance with an existing disk.
@@ -241,6 +448,13 @@ func (d *Driver) Start() error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
 	defer cancel()
 
+	extraMounts, err := parseExtraMounts(d.PodExtraMounts)
+	if err != nil {
+		return err
+	}
+
+	// Provisioning always happens over SSH regardless of PodConnection (see
+	// Create), so the public key always needs to be injected via cloud-init.
 	pubKeyData, err := ioutil.ReadFile(d.ResolveStorePath("id_rsa.pub"))
 	if err != nil {
 		return err
@@ -254,117 +468,133 @@ func (d *Driver) Start() error {
 		}
 	}
 
-	metadata, err := json.Marshal(map[string]interface{}{
-		"public-keys": []interface{}{
-			string(pubKeyData),
-		},
-	})
+	namespace, k8s, baseApply, err := getClient()
 	if err != nil {
 		return err
 	}
 
-	namespace, k8s, apply, err := getClient()
-	if err != nil {
-		return err
-	}
+	names := d.replicaNames()
+	svc := newHeadlessService(namespace, d.MachineName)
 
-	pod, secret := podAndSecret(namespace, d.MachineName, d.Image, userdata, metadata)
-	apply, os := getApply(ctx, apply, pod, secret)
+	for i, name := range names {
+		metadata, err := replicaMetadata(pubKeyData, namespace, d.MachineName, i, d.PodRole)
+		if err != nil {
+			return err
+		}
 
-	if err := apply.Apply(os); err != nil {
-		return err
-	}
+		// The state owner, Secret and PVC are applied first and owned by the
+		// state owner, not the Pod, so they rebind across Stop/Start cycles
+		// instead of being torn down with it.
+		owner, secret, pvc, err := stateObjects(namespace, d.MachineName, name, d.PodStorageClass, d.PodDiskSize, userdata, metadata)
+		if err != nil {
+			return err
+		}
 
-	w, err := k8s.CoreV1().Pods(namespace).Watch(ctx, metav1.ListOptions{
-		TimeoutSeconds: &[]int64{600}[0],
-	})
-	if err != nil {
-		return err
-	}
+		stateApply, stateOS := getStateApply(ctx, baseApply, owner, secret, pvc)
+		if err := stateApply.Apply(stateOS); err != nil {
+			return err
+		}
+
+		pod := newPod(namespace, name, d.Image, extraMounts)
+		pod.Spec.Subdomain = headlessServiceName(d.MachineName)
+		pod.Labels = driverLabels(d.MachineName)
+		if err := applyPodSpecFlags(pod, d); err != nil {
+			return err
+		}
 
-	for event := range w.ResultChan() {
-		if pod, ok := event.Object.(*corev1.Pod); ok {
-			if pod.Status.PodIP != "" {
-				d.IPAddress = pod.Status.PodIP
-				w.Stop()
-			}
+		var podApply apply.Apply
+		var podOS *objectset.ObjectSet
+		if i == 0 {
+			// The headless Service is owned by the lead pod, so it comes
+			// down together with it on Stop and is recreated on Start.
+			podApply, podOS = getApply(ctx, baseApply, pod, svc)
+		} else {
+			podApply, podOS = getApply(ctx, baseApply, pod)
+		}
+		if err := podApply.Apply(podOS); err != nil {
+			return err
 		}
 	}
 
-	if d.IPAddress == "" {
-		return fmt.Errorf("failed to get IP of %s/%s", namespace, d.MachineName)
+	for i, name := range names {
+		ip, err := waitForPodReady(ctx, k8s, namespace, name)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			d.IPAddress = ip
+		}
 	}
 
 	return nil
 }
 
-func podAndSecret(namespace, name, image string, userData, metaData []byte) (*corev1.Pod, *corev1.Secret) {
+// newPod builds the Pod for name, mounting the cloud-init Secret and the
+// rancher state PVC created alongside it by stateObjects.
+func newPod(namespace, name, image string, extraMounts []mountSpec) *corev1.Pod {
 	return &corev1.Pod{
-			TypeMeta: metav1.TypeMeta{
-				Kind:       "Pod",
-				APIVersion: "v1",
-			},
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      name,
-				Namespace: namespace,
-			},
-			Spec: corev1.PodSpec{
-				Volumes: []corev1.Volume{
-					{
-						Name: "data",
-						VolumeSource: corev1.VolumeSource{
-							Secret: &corev1.SecretVolumeSource{
-								SecretName: name,
-							},
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Pod",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{
+				{
+					Name: "data",
+					VolumeSource: corev1.VolumeSource{
+						Secret: &corev1.SecretVolumeSource{
+							SecretName: name,
 						},
 					},
 				},
-				Containers: []corev1.Container{{
-					Name:  "machine",
-					Image: image,
-					VolumeMounts: []corev1.VolumeMount{
-						{
-							Name:      "data",
-							MountPath: "/var/lib/cloud/seed/nocloud/meta-data",
-							SubPath:   "meta-data",
+				{
+					Name: "state",
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+							ClaimName: name + machineStateSuffix,
 						},
-						{
-							Name:      "data",
-							MountPath: "/var/lib/cloud/seed/nocloud/user-data",
-							SubPath:   "user-data",
-						},
-					},
-					SecurityContext: &corev1.SecurityContext{
-						Privileged: &[]bool{true}[0],
 					},
-					Stdin:     true,
-					StdinOnce: true,
-					TTY:       true,
-				}},
-				RestartPolicy:                 corev1.RestartPolicyNever,
-				AutomountServiceAccountToken:  new(bool),
-				Hostname:                      name,
-				TerminationGracePeriodSeconds: new(int64),
+				},
 			},
+			Containers: []corev1.Container{{
+				Name:  "machine",
+				Image: image,
+				VolumeMounts: append([]corev1.VolumeMount{
+					{
+						Name:      "data",
+						MountPath: "/var/lib/cloud/seed/nocloud/meta-data",
+						SubPath:   "meta-data",
+					},
+					{
+						Name:      "data",
+						MountPath: "/var/lib/cloud/seed/nocloud/user-data",
+						SubPath:   "user-data",
+					},
+				}, stateVolumeMounts(extraMounts)...),
+				SecurityContext: &corev1.SecurityContext{
+					Privileged: &[]bool{true}[0],
+				},
+				Stdin:     true,
+				StdinOnce: true,
+				TTY:       true,
+			}},
+			RestartPolicy:                 corev1.RestartPolicyNever,
+			AutomountServiceAccountToken:  new(bool),
+			Hostname:                      name,
+			TerminationGracePeriodSeconds: new(int64),
 		},
-		&corev1.Secret{
-			TypeMeta: metav1.TypeMeta{
-				Kind:       "Secret",
-				APIVersion: "v1",
-			},
-			ObjectMeta: metav1.ObjectMeta{
-				Namespace: namespace,
-				Name:      name,
-			},
-			Data: map[string][]byte{
-				"user-data": userData,
-				"meta-data": metaData,
-			},
-		}
+	}
 }
 
-func getApply(ctx context.Context, apply apply.Apply, pod *corev1.Pod, secret *corev1.Secret) (apply.Apply, *objectset.ObjectSet) {
-	os := objectset.NewObjectSet(pod, secret)
+// getApply scopes apply to manage pod and any extra objects (the lead pod's
+// headless Service), all owned by pod, so deleting them on Stop does not
+// touch the Secret/PVC owned by the state owner.
+func getApply(ctx context.Context, apply apply.Apply, pod *corev1.Pod, extra ...runtime.Object) (apply.Apply, *objectset.ObjectSet) {
+	os := objectset.NewObjectSet(append([]runtime.Object{pod}, extra...)...)
 	return apply.
 		WithDynamicLookup().
 		WithListerNamespace(pod.Namespace).
@@ -373,22 +603,32 @@ func getApply(ctx context.Context, apply apply.Apply, pod *corev1.Pod, secret *c
 		WithContext(ctx), os
 }
 
-// Stop stops an existing pod instance.
+// Stop deletes every replica Pod and the headless Service, but leaves each
+// replica's state owner, Secret and PVC in place so Start can rebind them.
 func (d *Driver) Stop() error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
 	defer cancel()
 
-	namespace, _, apply, err := getClient()
+	namespace, _, baseApply, err := getClient()
 	if err != nil {
 		return err
 	}
 
-	pod, secret := podAndSecret(namespace, d.MachineName, "", nil, nil)
-	apply, _ = getApply(ctx, apply, pod, secret)
+	svc := newHeadlessService(namespace, d.MachineName)
 
-	// Delete everything
-	if err := apply.ApplyObjects(); err != nil {
-		return err
+	for i, name := range d.replicaNames() {
+		pod := newPod(namespace, name, "", nil)
+
+		var apply apply.Apply
+		if i == 0 {
+			apply, _ = getApply(ctx, baseApply, pod, svc)
+		} else {
+			apply, _ = getApply(ctx, baseApply, pod)
+		}
+
+		if err := apply.ApplyObjects(); err != nil {
+			return err
+		}
 	}
 
 	d.IPAddress = ""
@@ -409,7 +649,25 @@ func (d *Driver) Kill() error {
 	return d.Stop()
 }
 
-// Remove deletes the Pod
+// Remove tears down the whole replica group: every replica Pod, and each
+// replica's state owner, Secret and PVC. Rather than reconstructing those
+// objects from the driver's current flags (which may have drifted since
+// Create, e.g. --pod-disk-size), it deletes everything matching
+// machineSelector, the per-machine narrowing of the driverSelector that
+// "prune" uses cluster-wide.
 func (d *Driver) Remove() error {
-	return d.Stop()
+	if err := d.Stop(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
+	defer cancel()
+
+	namespace, k8s, _, err := getClient()
+	if err != nil {
+		return err
+	}
+
+	_, err = pruneByLabel(ctx, k8s, namespace, machineSelector(d.MachineName), false)
+	return err
 }
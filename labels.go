@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/rancher/machine/libmachine/log"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// driverLabel marks every object this driver creates, regardless of
+	// which machine it belongs to, so prune can find them cluster-wide.
+	driverLabel = "docker-machine.rancher.io/driver"
+	// driverLabelValue is driverLabel's value; the driver only ever
+	// provisions "pod" kind machines.
+	driverLabelValue = "pod"
+	// driverMachineLabel scopes an object to a single machine's replica
+	// group. It doubles as the headless Service's pod selector.
+	driverMachineLabel = "docker-machine.rancher.io/machine"
+	// driverCreatedByLabel records which process created an object, for
+	// diagnosing orphans left behind by a driver process that died between
+	// Create and Start.
+	driverCreatedByLabel = "docker-machine.rancher.io/created-by"
+)
+
+// driverLabels is the fixed label set stamped on every object (Pod, Secret,
+// PVC, Service, state-owner ConfigMap) the driver creates for machineName.
+// Remove and the "prune" subcommand use it to find and delete objects by
+// selector instead of relying on the wrangler owner reconstructed in code,
+// which is fragile if e.g. the machine's disk size flag changed since Create.
+func driverLabels(machineName string) map[string]string {
+	return map[string]string{
+		driverLabel:          driverLabelValue,
+		driverMachineLabel:   machineName,
+		driverCreatedByLabel: createdBy(),
+	}
+}
+
+// labelValueRegexp matches runs of characters a Kubernetes label value may
+// not contain (anything other than alphanumerics, "-", "_" and ".").
+// createdBy replaces each such run with "-" to sanitize the hostname-based
+// value before stamping it on a label.
+var labelValueRegexp = regexp.MustCompile(`[^-A-Za-z0-9_.]+`)
+
+// maxLabelValueLen is the Kubernetes label value length limit.
+const maxLabelValueLen = 63
+
+// createdBy identifies the process that is about to create an object, as a
+// valid label value derived from "<hostname>-<pid>". Unlike "<hostname>/<pid>"
+// this never contains a "/", which the API server rejects in a label value.
+func createdBy() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	value := labelValueRegexp.ReplaceAllString(fmt.Sprintf("%s-%d", hostname, os.Getpid()), "-")
+	value = strings.Trim(value, "-_.")
+	if len(value) > maxLabelValueLen {
+		value = strings.Trim(value[:maxLabelValueLen], "-_.")
+	}
+	return value
+}
+
+// driverSelector is the label selector prune uses to find every object the
+// driver has ever created, across all of its machines.
+func driverSelector() string {
+	return labels.Set{driverLabel: driverLabelValue}.String()
+}
+
+// machineSelector is the label selector Remove uses to find every object
+// belonging to a single machine.
+func machineSelector(machineName string) string {
+	return labels.Set{driverLabel: driverLabelValue, driverMachineLabel: machineName}.String()
+}
+
+// prunedObject describes one object prune found (and, unless dryRun, deleted).
+type prunedObject struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+func (o prunedObject) String() string {
+	return fmt.Sprintf("%s %s/%s", o.Kind, o.Namespace, o.Name)
+}
+
+// pruneByLabel lists every Pod, Secret, PersistentVolumeClaim, Service and
+// ConfigMap matching selector in namespace (metav1.NamespaceAll for every
+// namespace), deleting them unless dryRun is set. It mirrors minikube's
+// oci.DeleteContainersByLabel/PruneAllVolumesByLabel pattern of garbage
+// collecting by label rather than by reconstructing the exact object that
+// was created.
+func pruneByLabel(ctx context.Context, k8s kubernetes.Interface, namespace, selector string, dryRun bool) ([]prunedObject, error) {
+	listOpts := metav1.ListOptions{LabelSelector: selector}
+
+	var pruned []prunedObject
+
+	pods, err := k8s.CoreV1().Pods(namespace).List(ctx, listOpts)
+	if err != nil {
+		return nil, fmt.Errorf("listing pods: %v", err)
+	}
+	for _, pod := range pods.Items {
+		pruned = append(pruned, prunedObject{"Pod", pod.Namespace, pod.Name})
+	}
+
+	secrets, err := k8s.CoreV1().Secrets(namespace).List(ctx, listOpts)
+	if err != nil {
+		return nil, fmt.Errorf("listing secrets: %v", err)
+	}
+	for _, secret := range secrets.Items {
+		pruned = append(pruned, prunedObject{"Secret", secret.Namespace, secret.Name})
+	}
+
+	pvcs, err := k8s.CoreV1().PersistentVolumeClaims(namespace).List(ctx, listOpts)
+	if err != nil {
+		return nil, fmt.Errorf("listing persistentvolumeclaims: %v", err)
+	}
+	for _, pvc := range pvcs.Items {
+		pruned = append(pruned, prunedObject{"PersistentVolumeClaim", pvc.Namespace, pvc.Name})
+	}
+
+	services, err := k8s.CoreV1().Services(namespace).List(ctx, listOpts)
+	if err != nil {
+		return nil, fmt.Errorf("listing services: %v", err)
+	}
+	for _, svc := range services.Items {
+		pruned = append(pruned, prunedObject{"Service", svc.Namespace, svc.Name})
+	}
+
+	configMaps, err := k8s.CoreV1().ConfigMaps(namespace).List(ctx, listOpts)
+	if err != nil {
+		return nil, fmt.Errorf("listing configmaps: %v", err)
+	}
+	for _, cm := range configMaps.Items {
+		pruned = append(pruned, prunedObject{"ConfigMap", cm.Namespace, cm.Name})
+	}
+
+	if dryRun {
+		return pruned, nil
+	}
+
+	for _, obj := range pruned {
+		log.Infof("pruning %s", obj)
+		var err error
+		switch obj.Kind {
+		case "Pod":
+			err = k8s.CoreV1().Pods(obj.Namespace).Delete(ctx, obj.Name, metav1.DeleteOptions{})
+		case "Secret":
+			err = k8s.CoreV1().Secrets(obj.Namespace).Delete(ctx, obj.Name, metav1.DeleteOptions{})
+		case "PersistentVolumeClaim":
+			err = k8s.CoreV1().PersistentVolumeClaims(obj.Namespace).Delete(ctx, obj.Name, metav1.DeleteOptions{})
+		case "Service":
+			err = k8s.CoreV1().Services(obj.Namespace).Delete(ctx, obj.Name, metav1.DeleteOptions{})
+		case "ConfigMap":
+			err = k8s.CoreV1().ConfigMaps(obj.Namespace).Delete(ctx, obj.Name, metav1.DeleteOptions{})
+		}
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("deleting %s: %v", obj, err)
+		}
+	}
+
+	return pruned, nil
+}
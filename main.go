@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -9,11 +10,54 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "prune" {
+		if err := runPrune(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	version := flag.Bool("v", false, "prints current docker-machine-driver-kubernetes version")
 	flag.Parse()
 	if *version {
 		fmt.Printf("Version: %s\n", "1.0.0")
 		os.Exit(0)
 	}
-	plugin.RegisterDriver(NewDriver("",""))
+	plugin.RegisterDriver(NewDriver("", ""))
+}
+
+// runPrune implements the "prune" subcommand: it deletes every Pod, Secret,
+// PersistentVolumeClaim, Service and ConfigMap this driver has ever created,
+// found by driverSelector() rather than by reconstructing what a particular
+// Create left behind. This catches orphans left by a driver process that
+// died between Create and Start, or by a machine docker-machine has already
+// forgotten about.
+func runPrune(args []string) error {
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	namespace := fs.String("namespace", "", "only prune objects in this namespace (default: every namespace)")
+	dryRun := fs.Bool("dry-run", false, "list what would be pruned without deleting anything")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	_, _, k8s, _, err := getClientConfig()
+	if err != nil {
+		return err
+	}
+
+	pruned, err := pruneByLabel(context.Background(), k8s, *namespace, driverSelector(), *dryRun)
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range pruned {
+		fmt.Println(obj)
+	}
+	if *dryRun {
+		fmt.Fprintf(os.Stderr, "dry-run: would prune %d object(s)\n", len(pruned))
+	} else {
+		fmt.Fprintf(os.Stderr, "pruned %d object(s)\n", len(pruned))
+	}
+	return nil
 }
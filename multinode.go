@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/rancher/machine/libmachine/state"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// rke2ServerPort is the port RKE2 servers listen on for other nodes to join.
+const rke2ServerPort = 9345
+
+// replicaNames returns the stable pod names for d's cluster: <machine>-0,
+// <machine>-1, ..., <machine>-N-1. Index 0 is always the lead pod that other
+// replicas join against.
+func (d *Driver) replicaNames() []string {
+	replicas := d.PodReplicas
+	if replicas <= 0 {
+		replicas = defaultPodReplicas
+	}
+
+	names := make([]string, replicas)
+	for i := range names {
+		names[i] = replicaName(d.MachineName, i)
+	}
+	return names
+}
+
+func replicaName(machineName string, i int) string {
+	return fmt.Sprintf("%s-%d", machineName, i)
+}
+
+func leadName(machineName string) string {
+	return replicaName(machineName, 0)
+}
+
+// headlessServiceName is the Service that gives every replica of machineName
+// a stable per-pod DNS name for peer discovery.
+func headlessServiceName(machineName string) string {
+	return machineName
+}
+
+// newHeadlessService returns the headless Service that fronts every replica
+// of machineName, so each gets a DNS name of
+// "<replica>.<machineName>.<namespace>.svc.cluster.local".
+func newHeadlessService(namespace, machineName string) *corev1.Service {
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      headlessServiceName(machineName),
+			Namespace: namespace,
+			Labels:    driverLabels(machineName),
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: corev1.ClusterIPNone,
+			Selector:  map[string]string{driverMachineLabel: machineName},
+			Ports: []corev1.ServicePort{
+				{Name: "docker", Port: 2376},
+			},
+		},
+	}
+}
+
+// joinAddress is the DNS name every non-lead replica joins the lead pod
+// through. It is resolvable as soon as the Service and the lead Pod exist,
+// without having to wait for the lead pod's IP first.
+func joinAddress(namespace, machineName string) string {
+	return fmt.Sprintf("%s.%s.%s.svc.cluster.local", leadName(machineName), headlessServiceName(machineName), namespace)
+}
+
+// replicaMetadata builds the cloud-init meta-data for the replica at index i.
+// Index 0 is the first RKE2 server and carries no join information; every
+// other replica joins the lead pod as podRole.
+func replicaMetadata(pubKeyData []byte, namespace, machineName string, i int, podRole string) ([]byte, error) {
+	name := replicaName(machineName, i)
+
+	metadata := map[string]interface{}{
+		"public-keys": []interface{}{
+			string(pubKeyData),
+		},
+		"local-hostname": name,
+	}
+
+	if i > 0 {
+		metadata["rke2-role"] = podRole
+		metadata["rke2-join-url"] = fmt.Sprintf("https://%s:%d", joinAddress(namespace, machineName), rke2ServerPort)
+	}
+
+	return json.Marshal(metadata)
+}
+
+// aggregateState reports the docker-machine state of the whole replica
+// group: Running only once every replica Pod exists, is Running and all of
+// its containers are Ready.
+func aggregateState(ctx context.Context, k8s kubernetes.Interface, namespace string, names []string) (state.State, error) {
+	anyFound := false
+	allRunning := true
+
+	for _, name := range names {
+		pod, err := k8s.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			allRunning = false
+			continue
+		} else if err != nil {
+			return state.None, err
+		}
+		anyFound = true
+
+		if pod.Status.Phase != corev1.PodRunning {
+			allRunning = false
+			continue
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			if !cs.Ready {
+				allRunning = false
+			}
+		}
+	}
+
+	if !anyFound {
+		return state.NotFound, nil
+	}
+	if allRunning {
+		return state.Running, nil
+	}
+	return state.Starting, nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/yaml"
+)
+
+// parseKeyValues parses a comma-separated "key=value" list, the format used
+// by --pod-node-selector, --pod-labels and --pod-annotations.
+func parseKeyValues(raw string) (map[string]string, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	values := map[string]string{}
+	for _, pair := range strings.Split(raw, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid key=value entry %q", pair)
+		}
+		values[parts[0]] = parts[1]
+	}
+
+	return values, nil
+}
+
+// parseTolerations parses the comma-separated --pod-tolerations value, each
+// entry of the form "key=value:Effect".
+func parseTolerations(raw string) ([]corev1.Toleration, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	var tolerations []corev1.Toleration
+	for _, entry := range strings.Split(raw, ",") {
+		keyValue, effect, ok := strings.Cut(entry, ":")
+		if !ok || effect == "" {
+			return nil, fmt.Errorf("invalid --pod-tolerations entry %q: expected key=value:Effect", entry)
+		}
+
+		key, value, ok := strings.Cut(keyValue, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --pod-tolerations entry %q: expected key=value:Effect", entry)
+		}
+
+		tolerations = append(tolerations, corev1.Toleration{
+			Key:      key,
+			Operator: corev1.TolerationOpEqual,
+			Value:    value,
+			Effect:   corev1.TaintEffect(effect),
+		})
+	}
+
+	return tolerations, nil
+}
+
+// parseResourceList builds a corev1.ResourceList from raw CPU/memory
+// quantity strings, omitting any that are empty. Used for both requests and
+// limits.
+func parseResourceList(cpu, memory string) (corev1.ResourceList, error) {
+	list := corev1.ResourceList{}
+
+	if cpu != "" {
+		qty, err := resource.ParseQuantity(cpu)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpu quantity %q: %v", cpu, err)
+		}
+		list[corev1.ResourceCPU] = qty
+	}
+
+	if memory != "" {
+		qty, err := resource.ParseQuantity(memory)
+		if err != nil {
+			return nil, fmt.Errorf("invalid memory quantity %q: %v", memory, err)
+		}
+		list[corev1.ResourceMemory] = qty
+	}
+
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	return list, nil
+}
+
+// parseAffinityFile reads path and unmarshals it, as either JSON or YAML,
+// into a corev1.Affinity to merge into the pod spec.
+func parseAffinityFile(path string) (*corev1.Affinity, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read affinity file %v: %v", path, err)
+	}
+
+	affinity := &corev1.Affinity{}
+	if err := yaml.UnmarshalStrict(data, affinity); err != nil {
+		return nil, fmt.Errorf("cannot parse affinity file %v: %v", path, err)
+	}
+
+	return affinity, nil
+}
+
+// imagePullSecrets splits --pod-image-pull-secret's comma-separated secret
+// names into LocalObjectReferences.
+func imagePullSecrets(raw string) []corev1.LocalObjectReference {
+	if raw == "" {
+		return nil
+	}
+
+	var refs []corev1.LocalObjectReference
+	for _, name := range strings.Split(raw, ",") {
+		refs = append(refs, corev1.LocalObjectReference{Name: name})
+	}
+
+	return refs
+}
+
+// applyPodSpecFlags layers the rich pod-spec flags (resources, scheduling,
+// image pull secrets, service account, priority/runtime class, extra
+// labels/annotations) onto pod, built by newPod. It sets no environment
+// variables.
+func applyPodSpecFlags(pod *corev1.Pod, d *Driver) error {
+	requests, err := parseResourceList(d.PodCPURequest, d.PodMemoryRequest)
+	if err != nil {
+		return err
+	}
+	limits, err := parseResourceList(d.PodCPULimit, d.PodMemoryLimit)
+	if err != nil {
+		return err
+	}
+	if requests != nil || limits != nil {
+		pod.Spec.Containers[0].Resources = corev1.ResourceRequirements{
+			Requests: requests,
+			Limits:   limits,
+		}
+	}
+
+	nodeSelector, err := parseKeyValues(d.PodNodeSelector)
+	if err != nil {
+		return err
+	}
+	pod.Spec.NodeSelector = nodeSelector
+
+	tolerations, err := parseTolerations(d.PodTolerations)
+	if err != nil {
+		return err
+	}
+	pod.Spec.Tolerations = tolerations
+
+	if d.PodAffinityFile != "" {
+		affinity, err := parseAffinityFile(d.PodAffinityFile)
+		if err != nil {
+			return err
+		}
+		pod.Spec.Affinity = affinity
+	}
+
+	pod.Spec.ImagePullSecrets = imagePullSecrets(d.PodImagePullSecret)
+
+	if d.PodServiceAccount != "" {
+		pod.Spec.ServiceAccountName = d.PodServiceAccount
+		pod.Spec.AutomountServiceAccountToken = nil
+	}
+
+	if d.PodPriorityClass != "" {
+		pod.Spec.PriorityClassName = d.PodPriorityClass
+	}
+
+	if d.PodRuntimeClass != "" {
+		pod.Spec.RuntimeClassName = &d.PodRuntimeClass
+	}
+
+	labels, err := parseKeyValues(d.PodLabels)
+	if err != nil {
+		return err
+	}
+	for k, v := range labels {
+		if pod.Labels == nil {
+			pod.Labels = map[string]string{}
+		}
+		pod.Labels[k] = v
+	}
+
+	annotations, err := parseKeyValues(d.PodAnnotations)
+	if err != nil {
+		return err
+	}
+	for k, v := range annotations {
+		if pod.Annotations == nil {
+			pod.Annotations = map[string]string{}
+		}
+		pod.Annotations[k] = v
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// startPortForward forwards podPort on the Pod to an ephemeral local port and
+// returns the chosen local port. The returned stopCh must be closed to tear
+// the forward down.
+//
+// This is the entirety of what --pod-connection=exec actually does: it backs
+// GetURL's "tcp://127.0.0.1:<localPort>" for reaching the docker daemon. It
+// does not give rancher/machine's bootstrapper an SSH-less way to run
+// commands on the Pod - that needs a hook into the bootstrapper's own
+// command-running path, which isn't available to this driver. Provisioning
+// therefore always happens over SSH (Create/Start always generate and inject
+// the key pair) regardless of PodConnection.
+func startPortForward(restConfig *rest.Config, k8s kubernetes.Interface, namespace, pod string, podPort int) (localPort int, stopCh chan struct{}, err error) {
+	roundTripper, upgrader, err := spdy.RoundTripperFor(restConfig)
+	if err != nil {
+		return 0, nil, fmt.Errorf("building SPDY round tripper for %s/%s: %v", namespace, pod, err)
+	}
+
+	req := k8s.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod).
+		SubResource("portforward")
+
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: roundTripper}, http.MethodPost, req.URL())
+
+	stopCh = make(chan struct{}, 1)
+	readyCh := make(chan struct{})
+	ports := []string{fmt.Sprintf("0:%d", podPort)}
+
+	fw, err := portforward.New(dialer, ports, stopCh, readyCh, ioutil.Discard, ioutil.Discard)
+	if err != nil {
+		return 0, nil, fmt.Errorf("setting up port-forward to %s/%s: %v", namespace, pod, err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- fw.ForwardPorts()
+	}()
+
+	select {
+	case err := <-errCh:
+		return 0, nil, fmt.Errorf("port-forward to %s/%s exited early: %v", namespace, pod, err)
+	case <-readyCh:
+	}
+
+	forwarded, err := fw.GetPorts()
+	if err != nil {
+		close(stopCh)
+		return 0, nil, fmt.Errorf("reading forwarded ports for %s/%s: %v", namespace, pod, err)
+	}
+
+	return int(forwarded[0].Local), stopCh, nil
+}
+
+// getOrCreatePortForward lazily establishes the local portforward used by
+// GetURL in exec mode and returns the local port that now proxies to 2376 on
+// the Pod.
+func (d *Driver) getOrCreatePortForward() (int, error) {
+	if d.localPort != 0 {
+		return d.localPort, nil
+	}
+
+	namespace, restConfig, k8s, _, err := getClientConfig()
+	if err != nil {
+		return 0, err
+	}
+
+	port, stopCh, err := startPortForward(restConfig, k8s, namespace, leadName(d.MachineName), 2376)
+	if err != nil {
+		return 0, err
+	}
+
+	d.localPort = port
+	d.stopExec = stopCh
+	return port, nil
+}
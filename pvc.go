@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rancher/wrangler/pkg/apply"
+	"github.com/rancher/wrangler/pkg/objectset"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// machineStateSuffix names the objects that back a machine's persistent
+// rancher state. They are owned by the machine-state ConfigMap rather than
+// the Pod, so deleting the Pod on Stop does not take the PVC (or Secret)
+// down with it; only Remove tears them down.
+const machineStateSuffix = "-state"
+
+// mountSpec is one "hostPath:mountPath" pair from --pod-extra-mounts: a
+// subPath on the state PVC, and where to mount it inside the pod.
+type mountSpec struct {
+	subPath   string
+	mountPath string
+}
+
+// parseExtraMounts parses the comma-separated --pod-extra-mounts value.
+func parseExtraMounts(raw string) ([]mountSpec, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	var mounts []mountSpec
+	for _, pair := range strings.Split(raw, ",") {
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid --pod-extra-mounts entry %q: expected hostPath:mountPath", pair)
+		}
+		mounts = append(mounts, mountSpec{subPath: parts[0], mountPath: parts[1]})
+	}
+
+	return mounts, nil
+}
+
+// stateOwner returns the ConfigMap used purely as a stable owner reference
+// for the PVC (and Secret) backing name's rancher state, so they outlive the
+// Pod across Stop/Start cycles.
+func stateOwner(namespace, machineName, name string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name + machineStateSuffix,
+			Namespace: namespace,
+			Labels:    driverLabels(machineName),
+		},
+	}
+}
+
+// statePVC returns the PersistentVolumeClaim that backs name's rancher
+// state. It is owned by the stateOwner ConfigMap, not the Pod.
+func statePVC(namespace, machineName, name, storageClass, diskSize string) (*corev1.PersistentVolumeClaim, error) {
+	size, err := resource.ParseQuantity(diskSize)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --pod-disk-size %q: %v", diskSize, err)
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "PersistentVolumeClaim",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name + machineStateSuffix,
+			Namespace: namespace,
+			Labels:    driverLabels(machineName),
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: size,
+				},
+			},
+		},
+	}
+
+	if storageClass != "" {
+		pvc.Spec.StorageClassName = &storageClass
+	}
+
+	return pvc, nil
+}
+
+// rancherStateMountPaths are the directories RKE2 writes persistent state
+// under; they're mounted from the state PVC so they survive a Restart.
+var rancherStateMountPaths = []string{
+	"/var/lib/rancher",
+	"/etc/rancher",
+	"/var/lib/kubelet",
+}
+
+// stateVolumeMounts returns the VolumeMounts for the "state" PVC volume: the
+// well-known rancher state directories, plus any --pod-extra-mounts entries.
+func stateVolumeMounts(extraMounts []mountSpec) []corev1.VolumeMount {
+	mounts := make([]corev1.VolumeMount, 0, len(rancherStateMountPaths)+len(extraMounts))
+	for _, path := range rancherStateMountPaths {
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      "state",
+			MountPath: path,
+			SubPath:   strings.TrimPrefix(path, "/"),
+		})
+	}
+	for _, m := range extraMounts {
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      "state",
+			MountPath: m.mountPath,
+			SubPath:   m.subPath,
+		})
+	}
+	return mounts
+}
+
+// stateObjects builds the state owner ConfigMap, cloud-init Secret and
+// rancher state PVC for name, one replica of machineName's cluster. All
+// three are owned by the returned ConfigMap rather than the Pod, and are
+// stamped with driverLabels(machineName) so Remove and "prune" can find them
+// by selector instead of reconstructing them from the current flags.
+func stateObjects(namespace, machineName, name, storageClass, diskSize string, userData, metaData []byte) (*corev1.ConfigMap, *corev1.Secret, *corev1.PersistentVolumeClaim, error) {
+	pvc, err := statePVC(namespace, machineName, name, storageClass, diskSize)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    driverLabels(machineName),
+		},
+		Data: map[string][]byte{
+			"user-data": userData,
+			"meta-data": metaData,
+		},
+	}
+
+	return stateOwner(namespace, machineName, name), secret, pvc, nil
+}
+
+// getStateApply scopes apply to manage the state owner, Secret and PVC,
+// owned by the state owner ConfigMap rather than the Pod.
+func getStateApply(ctx context.Context, apply apply.Apply, owner *corev1.ConfigMap, secret *corev1.Secret, pvc *corev1.PersistentVolumeClaim) (apply.Apply, *objectset.ObjectSet) {
+	os := objectset.NewObjectSet(owner, secret, pvc)
+	return apply.
+		WithDynamicLookup().
+		WithListerNamespace(owner.Namespace).
+		WithOwner(owner).
+		WithGVK(os.GVKs()...).
+		WithContext(ctx), os
+}
@@ -0,0 +1,139 @@
+//go:build integration
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// TestPVCSurvivesRestart is an integration test for the PVC-backed rancher
+// state added in stateObjects/getStateApply: it writes a file under
+// /var/lib/rancher in one Pod, deletes that Pod the way Stop does (leaving
+// the state owner ConfigMap, Secret and PVC in place), recreates it the way
+// Start does, and asserts the file is still there.
+//
+// It talks to a real apiserver and needs a cluster with the "local-path"
+// StorageClass available (e.g. a kind cluster with
+// rancher/local-path-provisioner installed), so it is only built with
+// -tags integration and, even then, is skipped unless
+// RUN_INTEGRATION_TESTS=1 is set.
+func TestPVCSurvivesRestart(t *testing.T) {
+	if os.Getenv("RUN_INTEGRATION_TESTS") == "" {
+		t.Skip("set RUN_INTEGRATION_TESTS=1 to run against a live cluster")
+	}
+
+	namespace, restConfig, k8s, _, err := getClientConfig()
+	if err != nil {
+		t.Fatalf("loading kubeconfig: %v", err)
+	}
+
+	machineName := fmt.Sprintf("pvc-restart-test-%d", os.Getpid())
+	name := leadName(machineName)
+	storageClass := os.Getenv("INTEGRATION_STORAGE_CLASS")
+	if storageClass == "" {
+		storageClass = "local-path"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	t.Cleanup(func() {
+		cleanupCtx, cleanupCancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cleanupCancel()
+		_, _ = pruneByLabel(cleanupCtx, k8s, namespace, machineSelector(machineName), false)
+	})
+
+	owner, secret, pvc, err := stateObjects(namespace, machineName, name, storageClass, defaultPodDiskSize, nil, []byte(`{"local-hostname":"`+name+`"}`))
+	if err != nil {
+		t.Fatalf("building state objects: %v", err)
+	}
+	if _, err := k8s.CoreV1().ConfigMaps(namespace).Create(ctx, owner, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("creating state owner: %v", err)
+	}
+	if _, err := k8s.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("creating secret: %v", err)
+	}
+	if _, err := k8s.CoreV1().PersistentVolumeClaims(namespace).Create(ctx, pvc, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("creating pvc: %v", err)
+	}
+
+	createPod := func() {
+		pod := newPod(namespace, name, defaultImage, nil)
+		if _, err := k8s.CoreV1().Pods(namespace).Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("creating pod: %v", err)
+		}
+		if _, err := waitForPodReady(ctx, k8s, namespace, name); err != nil {
+			t.Fatalf("waiting for pod to become ready: %v", err)
+		}
+	}
+
+	createPod()
+
+	marker := "/var/lib/rancher/pvc-restart-test"
+	if _, err := execInPod(restConfig, k8s, namespace, name, fmt.Sprintf("mkdir -p %s && echo survived > %s/marker", marker, marker)); err != nil {
+		t.Fatalf("writing marker file: %v", err)
+	}
+
+	// Stop only deletes the Pod; the state owner, Secret and PVC stay behind.
+	if err := k8s.CoreV1().Pods(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("deleting pod: %v", err)
+	}
+	if err := wait.PollImmediate(time.Second, time.Minute, func() (bool, error) {
+		_, err := k8s.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		return apierrors.IsNotFound(err), nil
+	}); err != nil {
+		t.Fatalf("waiting for pod deletion: %v", err)
+	}
+
+	createPod()
+
+	out, err := execInPod(restConfig, k8s, namespace, name, "cat "+marker+"/marker")
+	if err != nil {
+		t.Fatalf("reading marker file after restart: %v", err)
+	}
+	if out != "survived\n" {
+		t.Fatalf("marker file did not survive restart: got %q", out)
+	}
+}
+
+// execInPod runs command in name's "machine" container over the exec
+// subresource and returns its combined stdout/stderr. It exists only for
+// this test: the driver itself never exercises the exec subresource (see
+// portforward.go).
+func execInPod(restConfig *rest.Config, k8s kubernetes.Interface, namespace, name, command string) (string, error) {
+	req := k8s.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: "machine",
+			Command:   []string{"/bin/sh", "-c", command},
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(restConfig, http.MethodPost, req.URL())
+	if err != nil {
+		return "", fmt.Errorf("creating SPDY executor for %s/%s: %v", namespace, name, err)
+	}
+
+	var out bytes.Buffer
+	err = executor.Stream(remotecommand.StreamOptions{Stdout: &out, Stderr: &out})
+	return out.String(), err
+}